@@ -0,0 +1,141 @@
+package sponge
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+/*
+Cache is the pluggable storage backend behind a SpongeHandler. Implementations
+own their own eviction and expiration behavior -- SetCache passes a ttl
+computed from CacheExtraExpiration (and, per-key, from a SpongeExpirer) and
+trusts the Cache to honor it.
+*/
+type Cache interface {
+	// Get looks up a cached result. The second return value is false if the
+	// key is absent or has expired.
+	Get(key string) (SpongeProxyResult, bool)
+	// Set stores a result under key. A zero ttl means the entry never expires
+	// on its own.
+	Set(key string, val SpongeProxyResult, ttl time.Duration)
+	// Delete removes a single entry, if present.
+	Delete(key string)
+	// Purge empties the cache.
+	Purge()
+}
+
+// DefaultMaxEntries is the eviction cap used by NewLRUCache when none is given.
+const DefaultMaxEntries = 10000
+
+/*
+LRUCache is the default Cache implementation: an in-memory, size-bounded store
+that evicts the least-recently-used entry once MaxEntries is exceeded. It is
+safe for concurrent use.
+*/
+type LRUCache struct {
+	// MaxEntries is the maximum number of entries retained before the oldest
+	// is evicted on insert.
+	MaxEntries int
+
+	mutex sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     SpongeProxyResult
+	expiresAt time.Time
+}
+
+// NewLRUCache constructs an LRUCache. A maxEntries of 0 or less falls back to
+// DefaultMaxEntries.
+func NewLRUCache(maxEntries int) *LRUCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &LRUCache{
+		MaxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (SpongeProxyResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *LRUCache) Set(key string, val SpongeProxyResult, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		entry := el.Value.(*lruEntry)
+		entry.value = val
+		entry.expiresAt = expiresAt
+		return
+	}
+
+	el := c.ll.PushFront(&lruEntry{key: key, value: val, expiresAt: expiresAt})
+	c.items[key] = el
+
+	if c.ll.Len() > c.MaxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LRUCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *LRUCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ll = list.New()
+	c.items = make(map[string]*list.Element)
+}
+
+// Len reports the current number of entries, satisfying CacheSizer.
+func (c *LRUCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *LRUCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	entry := el.Value.(*lruEntry)
+	delete(c.items, entry.key)
+}
+
+var _ Cache = (*LRUCache)(nil)
@@ -0,0 +1,43 @@
+package sponge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", countingResult{val: 1}, 0)
+	c.Set("b", countingResult{val: 2}, 0)
+
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be present")
+	}
+
+	c.Set("c", countingResult{val: 3}, 0)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to have been evicted as least recently used")
+	}
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to survive eviction")
+	}
+
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("expected c to be present")
+	}
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	c := NewLRUCache(0)
+	c.Set("k", countingResult{val: 1}, time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("expected an expired entry to miss")
+	}
+}
@@ -0,0 +1,58 @@
+package sponge
+
+import (
+	"net/http"
+	"time"
+)
+
+/*
+ConditionalProxyResult is an optional extension of SpongeProxyResult that
+exposes the validators needed to drive conditional GET requests.
+*/
+type ConditionalProxyResult interface {
+	SpongeProxyResult
+	// ETag returns the validator to send as If-None-Match, or "" if unset.
+	ETag() string
+	// LastModified returns the validator to send as If-Modified-Since, or the
+	// zero time if unset.
+	LastModified() time.Time
+}
+
+/*
+SpongeConditionalProxy is an optional extension of SpongeProxy for backends
+that honor conditional GET semantics. When Proxy implements this, checkTick
+uses MakeConditionalBackendRequest instead of MakeBackendRequest once there's
+a cached result to validate against, giving backends that support it a cheap
+"nothing changed" response instead of a full poll-and-Equal cycle.
+*/
+type SpongeConditionalProxy interface {
+	SpongeProxy
+
+	/*
+		MakeConditionalBackendRequest issues a conditional request. request
+		already carries If-None-Match/If-Modified-Since set from prev's
+		validators, if prev implements ConditionalProxyResult. changed being
+		false (typically backed by a 304 response) means the backend confirmed
+		nothing changed; result is ignored in that case in favor of prev.
+	*/
+	MakeConditionalBackendRequest(request *http.Request, prev SpongeProxyResult) (result SpongeProxyResult, changed bool, err error)
+}
+
+/*
+setConditionalHeaders populates If-None-Match/If-Modified-Since on request
+from prev's validators, if it exposes any via ConditionalProxyResult.
+*/
+func setConditionalHeaders(request *http.Request, prev SpongeProxyResult) {
+	condResult, ok := prev.(ConditionalProxyResult)
+	if !ok {
+		return
+	}
+
+	if etag := condResult.ETag(); etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified := condResult.LastModified(); !lastModified.IsZero() {
+		request.Header.Set("If-Modified-Since", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
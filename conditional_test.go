@@ -0,0 +1,83 @@
+package sponge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type conditionalResult struct {
+	val          int
+	etag         string
+	lastModified time.Time
+}
+
+func (r conditionalResult) WriteToHTTP(w http.ResponseWriter) error { return nil }
+
+func (r conditionalResult) Equal(other SpongeProxyResult) bool {
+	o, ok := other.(conditionalResult)
+	return ok && o.val == r.val
+}
+
+func (r conditionalResult) ETag() string { return r.etag }
+
+func (r conditionalResult) LastModified() time.Time { return r.lastModified }
+
+var _ ConditionalProxyResult = conditionalResult{}
+
+type conditionalProxy struct {
+	changed         bool
+	result          SpongeProxyResult
+	seenIfNoneMatch string
+}
+
+func (p *conditionalProxy) MakeCacheKey(request *http.Request) string { return "k" }
+
+func (p *conditionalProxy) MakeBackendRequest(request *http.Request) (SpongeProxyResult, error) {
+	return p.result, nil
+}
+
+func (p *conditionalProxy) HandleError(err error, writer http.ResponseWriter) {}
+
+func (p *conditionalProxy) MakeConditionalBackendRequest(request *http.Request, prev SpongeProxyResult) (SpongeProxyResult, bool, error) {
+	p.seenIfNoneMatch = request.Header.Get("If-None-Match")
+	if !p.changed {
+		return nil, false, nil
+	}
+	return p.result, true, nil
+}
+
+var _ SpongeConditionalProxy = (*conditionalProxy)(nil)
+
+// A 304 (changed=false) must short-circuit to the cached result instead of
+// whatever the backend returned.
+func TestFetchBackendResultUsesCachedResultOn304(t *testing.T) {
+	prev := conditionalResult{val: 1, etag: `"abc"`}
+	proxy := &conditionalProxy{changed: false}
+	sh := &SpongeHandler{Proxy: proxy}
+	sh.Init(nil)
+	sh.cache.Set("k", prev, 0)
+
+	inbound := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	result, changed, err := sh.fetchBackendResult("k", inbound)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Fatal("expected changed=false on a 304")
+	}
+	if result != SpongeProxyResult(prev) {
+		t.Fatalf("expected prev to be returned on a 304, got %v", result)
+	}
+	if proxy.seenIfNoneMatch != `"abc"` {
+		t.Fatalf("expected If-None-Match to carry prev's etag, got %q", proxy.seenIfNoneMatch)
+	}
+
+	// setConditionalHeaders must be applied to a private clone, not the
+	// caller's original inbound request.
+	if inbound.Header.Get("If-None-Match") != "" {
+		t.Fatal("fetchBackendResult must not mutate the caller's original request")
+	}
+}
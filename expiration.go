@@ -0,0 +1,72 @@
+package sponge
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+SpongeExpirer is an optional interface a SpongeProxy can implement to override
+SpongeHandler's global expiration (CacheExtraExpiration + TickCount*TickTime)
+on a per-key basis -- useful when upstream responses carry their own
+Cache-Control freshness lifetime, or when some endpoints should be cached for
+seconds and others for hours.
+*/
+type SpongeExpirer interface {
+	// Expiration returns how long result should be cached for this request.
+	Expiration(request *http.Request, result SpongeProxyResult) time.Duration
+}
+
+/*
+ParseCacheControl extracts a freshness lifetime from a Cache-Control header,
+preferring s-maxage over max-age as shared caches should. It returns false if
+neither directive is present or parseable, so callers can fall back to their
+own default.
+
+Intended to be called from within a SpongeExpirer.Expiration implementation:
+
+    func (p MyProxy) Expiration(r *http.Request, result sponge.SpongeProxyResult) time.Duration {
+        if ttl, ok := sponge.ParseCacheControl(result.(MyResult).Header); ok {
+            return ttl
+        }
+        return time.Minute
+    }
+*/
+func ParseCacheControl(header http.Header) (time.Duration, bool) {
+	cc := header.Get("Cache-Control")
+	if cc == "" {
+		return 0, false
+	}
+
+	if seconds, ok := cacheControlDirective(cc, "s-maxage"); ok {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if seconds, ok := cacheControlDirective(cc, "max-age"); ok {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	return 0, false
+}
+
+func cacheControlDirective(cacheControl, name string) (int, bool) {
+	for _, part := range strings.Split(cacheControl, ",") {
+		part = strings.TrimSpace(part)
+		prefix := name + "="
+		if !strings.HasPrefix(part, prefix) {
+			continue
+		}
+		value := strings.TrimPrefix(part, prefix)
+
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false
+		}
+
+		return seconds, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,36 @@
+package sponge
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseCacheControlPrefersSMaxAge(t *testing.T) {
+	header := http.Header{}
+	header.Set("Cache-Control", "max-age=60, s-maxage=30")
+
+	ttl, ok := ParseCacheControl(header)
+	if !ok || ttl != 30*time.Second {
+		t.Fatalf("expected s-maxage=30s to win, got %v ok=%v", ttl, ok)
+	}
+}
+
+func TestParseCacheControlFallsBackToMaxAge(t *testing.T) {
+	header := http.Header{}
+	header.Set("Cache-Control", "max-age=45")
+
+	ttl, ok := ParseCacheControl(header)
+	if !ok || ttl != 45*time.Second {
+		t.Fatalf("expected max-age=45s, got %v ok=%v", ttl, ok)
+	}
+}
+
+func TestParseCacheControlMissingDirectives(t *testing.T) {
+	header := http.Header{}
+	header.Set("Cache-Control", "no-store")
+
+	if _, ok := ParseCacheControl(header); ok {
+		t.Fatal("expected no ttl for a header without max-age/s-maxage")
+	}
+}
@@ -0,0 +1,170 @@
+package sponge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+CacheSizer is an optional extension a Cache can implement to report its
+current entry count, backing the sponge_cache_entries gauge. LRUCache
+implements it; RedisCache does not, since Redis already exposes its own size
+metrics.
+*/
+type CacheSizer interface {
+	Len() int
+}
+
+/*
+Metrics holds the Prometheus collectors for a SpongeHandler's cache and
+backend activity. Construct one with NewMetrics, register it with Register,
+and assign it to SpongeHandler.Metrics. Every method is nil-safe, so a
+SpongeHandler with Metrics left unset pays no cost.
+*/
+type Metrics struct {
+	CacheHits        prometheus.Counter
+	CacheMisses      prometheus.Counter
+	BackendRequests  *prometheus.CounterVec // labeled by "result": hit|miss|state_change|error
+	BackendLatency   prometheus.Histogram
+	CacheEntries     prometheus.Gauge
+	TickStateChanges prometheus.Counter
+
+	mutex    sync.Mutex
+	snapshot Stats
+}
+
+// NewMetrics constructs a Metrics with the standard sponge collectors, ready
+// to Register.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		CacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sponge_cache_hits_total",
+			Help: "Requests served directly from cache.",
+		}),
+		CacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sponge_cache_misses_total",
+			Help: "Requests that missed the cache and triggered a backend poll.",
+		}),
+		BackendRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sponge_backend_requests_total",
+			Help: "Backend requests made by the tick loop, labeled by result.",
+		}, []string{"result"}),
+		BackendLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "sponge_backend_latency_seconds",
+			Help: "Latency of backend requests made by the tick loop.",
+		}),
+		CacheEntries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "sponge_cache_entries",
+			Help: "Current number of entries held by the cache, where the Cache implementation reports it.",
+		}),
+		TickStateChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "sponge_tick_state_changes_total",
+			Help: "Times a tick observed the backend's result change.",
+		}),
+		snapshot: Stats{BackendRequests: make(map[string]int64)},
+	}
+}
+
+// Register attaches every collector to reg.
+func (m *Metrics) Register(reg prometheus.Registerer) {
+	if m == nil {
+		return
+	}
+
+	reg.MustRegister(m.CacheHits, m.CacheMisses, m.BackendRequests, m.BackendLatency, m.CacheEntries, m.TickStateChanges)
+}
+
+// Stats is a point-in-time snapshot of Metrics, for users who don't run
+// Prometheus.
+type Stats struct {
+	CacheHits        int64
+	CacheMisses      int64
+	BackendRequests  map[string]int64
+	CacheEntries     int64
+	TickStateChanges int64
+}
+
+// Stats returns a snapshot of the counters and gauges recorded so far.
+func (m *Metrics) Stats() Stats {
+	if m == nil {
+		return Stats{}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	requests := make(map[string]int64, len(m.snapshot.BackendRequests))
+	for result, count := range m.snapshot.BackendRequests {
+		requests[result] = count
+	}
+
+	stats := m.snapshot
+	stats.BackendRequests = requests
+	return stats
+}
+
+func (m *Metrics) recordCacheHit() {
+	if m == nil {
+		return
+	}
+
+	m.CacheHits.Inc()
+
+	m.mutex.Lock()
+	m.snapshot.CacheHits++
+	m.mutex.Unlock()
+}
+
+func (m *Metrics) recordCacheMiss() {
+	if m == nil {
+		return
+	}
+
+	m.CacheMisses.Inc()
+
+	m.mutex.Lock()
+	m.snapshot.CacheMisses++
+	m.mutex.Unlock()
+}
+
+func (m *Metrics) recordBackendRequest(result string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.BackendRequests.WithLabelValues(result).Inc()
+	m.BackendLatency.Observe(latency.Seconds())
+
+	m.mutex.Lock()
+	if m.snapshot.BackendRequests == nil {
+		m.snapshot.BackendRequests = make(map[string]int64)
+	}
+	m.snapshot.BackendRequests[result]++
+	m.mutex.Unlock()
+}
+
+func (m *Metrics) recordStateChange() {
+	if m == nil {
+		return
+	}
+
+	m.TickStateChanges.Inc()
+
+	m.mutex.Lock()
+	m.snapshot.TickStateChanges++
+	m.mutex.Unlock()
+}
+
+func (m *Metrics) setCacheEntries(n int64) {
+	if m == nil {
+		return
+	}
+
+	m.CacheEntries.Set(float64(n))
+
+	m.mutex.Lock()
+	m.snapshot.CacheEntries = n
+	m.mutex.Unlock()
+}
@@ -0,0 +1,30 @@
+package sponge
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A Metrics literal built by hand -- populating the exported collector
+// fields directly instead of going through NewMetrics -- can't set the
+// unexported snapshot field, leaving its BackendRequests map nil.
+// recordBackendRequest must initialize that map lazily rather than assume
+// NewMetrics ran.
+func TestMetricsRecordBackendRequestOnZeroValueSnapshot(t *testing.T) {
+	m := &Metrics{
+		CacheHits:        prometheus.NewCounter(prometheus.CounterOpts{Name: "hits"}),
+		CacheMisses:      prometheus.NewCounter(prometheus.CounterOpts{Name: "misses"}),
+		BackendRequests:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "backend"}, []string{"result"}),
+		BackendLatency:   prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency"}),
+		CacheEntries:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "entries"}),
+		TickStateChanges: prometheus.NewCounter(prometheus.CounterOpts{Name: "changes"}),
+	}
+
+	m.recordBackendRequest("hit", 0)
+
+	stats := m.Stats()
+	if stats.BackendRequests["hit"] != 1 {
+		t.Fatalf("expected 1 recorded hit, got %d", stats.BackendRequests["hit"])
+	}
+}
@@ -0,0 +1,203 @@
+package sponge
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/*
+SelectionStrategy chooses which upstream SpongeUpstreamPool.Next hands back.
+*/
+type SelectionStrategy int
+
+const (
+	// RoundRobin cycles through healthy upstreams in order.
+	RoundRobin SelectionStrategy = iota
+	// Random picks a healthy upstream uniformly at random.
+	Random
+	// LeastInFlight picks the healthy upstream with the fewest requests
+	// currently in flight through the pool.
+	LeastInFlight
+)
+
+// ErrNoUpstreams is returned by SpongeUpstreamPool.Next when every upstream
+// in the pool is marked down.
+var ErrNoUpstreams = errors.New("sponge: no healthy upstreams available")
+
+/*
+SpongeUpstreamPool is meant to be embedded in a SpongeProxy implementation
+that fronts more than one upstream. It tracks upstream health and hands out a
+http.RoundTripper, pre-aimed at the chosen upstream, for MakeBackendRequest to
+use:
+
+    rt, err := p.pool.Next(request)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := rt.RoundTrip(request)
+
+Calling Next on every MakeBackendRequest -- including from within checkTick's
+per-tick polling -- naturally spreads successive ticks across upstreams.
+*/
+type SpongeUpstreamPool struct {
+	// Upstreams is the fixed set of backends to select from.
+	Upstreams []*url.URL
+	// Strategy determines how Next picks among healthy upstreams. Defaults to
+	// RoundRobin.
+	Strategy SelectionStrategy
+	// HealthCheckInterval is how often StartHealthChecks pings each upstream.
+	// Defaults to 30 seconds.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds each health check request. Defaults to 5
+	// seconds.
+	HealthCheckTimeout time.Duration
+	// Transport underlies every RoundTripper Next returns, and every health
+	// check request. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mutex    sync.Mutex
+	rrIndex  int
+	down     map[string]bool
+	inFlight map[string]int64
+}
+
+// Next selects a healthy upstream per Strategy and returns a RoundTripper
+// aimed at it. It returns ErrNoUpstreams if every upstream is down.
+func (p *SpongeUpstreamPool) Next(request *http.Request) (http.RoundTripper, error) {
+	p.mutex.Lock()
+	upstream := p.pick()
+	p.mutex.Unlock()
+
+	if upstream == nil {
+		return nil, ErrNoUpstreams
+	}
+
+	return &upstreamRoundTripper{pool: p, upstream: upstream}, nil
+}
+
+// pick chooses the next healthy upstream. Callers must hold p.mutex.
+func (p *SpongeUpstreamPool) pick() *url.URL {
+	healthy := make([]*url.URL, 0, len(p.Upstreams))
+	for _, u := range p.Upstreams {
+		if !p.down[u.String()] {
+			healthy = append(healthy, u)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	switch p.Strategy {
+	case Random:
+		return healthy[rand.Intn(len(healthy))]
+	case LeastInFlight:
+		best := healthy[0]
+		for _, u := range healthy[1:] {
+			if p.inFlight[u.String()] < p.inFlight[best.String()] {
+				best = u
+			}
+		}
+		return best
+	default:
+		u := healthy[p.rrIndex%len(healthy)]
+		p.rrIndex++
+		return u
+	}
+}
+
+func (p *SpongeUpstreamPool) transport() http.RoundTripper {
+	if p.Transport != nil {
+		return p.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (p *SpongeUpstreamPool) markDown(u *url.URL, down bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.down == nil {
+		p.down = make(map[string]bool)
+	}
+	p.down[u.String()] = down
+}
+
+func (p *SpongeUpstreamPool) addInFlight(u *url.URL, delta int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.inFlight == nil {
+		p.inFlight = make(map[string]int64)
+	}
+	p.inFlight[u.String()] += delta
+}
+
+/*
+StartHealthChecks runs a background goroutine that periodically HEADs every
+upstream and marks it down on failure or a 5xx response. Downed upstreams are skipped by Next
+until a later check finds them healthy again.
+*/
+func (p *SpongeUpstreamPool) StartHealthChecks() {
+	interval := p.HealthCheckInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	go func() {
+		for {
+			for _, u := range p.Upstreams {
+				p.checkUpstream(u)
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (p *SpongeUpstreamPool) checkUpstream(u *url.URL) {
+	timeout := p.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	client := &http.Client{Transport: p.transport(), Timeout: timeout}
+
+	resp, err := client.Head(u.String())
+	if err != nil {
+		p.markDown(u, true)
+		return
+	}
+	resp.Body.Close()
+
+	p.markDown(u, resp.StatusCode >= 500)
+}
+
+/*
+upstreamRoundTripper rewrites a request's scheme/host to its pool-assigned
+upstream before delegating, tracking in-flight count for LeastInFlight and
+marking the upstream down on transport-level failure.
+*/
+type upstreamRoundTripper struct {
+	pool     *SpongeUpstreamPool
+	upstream *url.URL
+}
+
+func (rt *upstreamRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	rt.pool.addInFlight(rt.upstream, 1)
+	defer rt.pool.addInFlight(rt.upstream, -1)
+
+	proxied := request.Clone(request.Context())
+	proxied.URL.Scheme = rt.upstream.Scheme
+	proxied.URL.Host = rt.upstream.Host
+	proxied.Host = rt.upstream.Host
+
+	resp, err := rt.pool.transport().RoundTrip(proxied)
+	if err != nil {
+		rt.pool.markDown(rt.upstream, true)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,61 @@
+package sponge
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSpongeUpstreamPoolSkipsDownUpstream(t *testing.T) {
+	up1, _ := url.Parse("http://a.example")
+	up2, _ := url.Parse("http://b.example")
+
+	pool := &SpongeUpstreamPool{Upstreams: []*url.URL{up1, up2}}
+	pool.markDown(up1, true)
+
+	rt, err := pool.Next(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	urt, ok := rt.(*upstreamRoundTripper)
+	if !ok {
+		t.Fatalf("expected *upstreamRoundTripper, got %T", rt)
+	}
+	if urt.upstream != up2 {
+		t.Fatalf("expected the healthy upstream %v, got %v", up2, urt.upstream)
+	}
+}
+
+func TestSpongeUpstreamPoolNextErrorsWhenAllDown(t *testing.T) {
+	up1, _ := url.Parse("http://a.example")
+
+	pool := &SpongeUpstreamPool{Upstreams: []*url.URL{up1}}
+	pool.markDown(up1, true)
+
+	if _, err := pool.Next(nil); err != ErrNoUpstreams {
+		t.Fatalf("expected ErrNoUpstreams, got %v", err)
+	}
+}
+
+func TestSpongeUpstreamPoolRoundRobinCyclesUpstreams(t *testing.T) {
+	up1, _ := url.Parse("http://a.example")
+	up2, _ := url.Parse("http://b.example")
+
+	pool := &SpongeUpstreamPool{Upstreams: []*url.URL{up1, up2}}
+
+	var seen []*url.URL
+	for i := 0; i < 4; i++ {
+		rt, err := pool.Next(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen = append(seen, rt.(*upstreamRoundTripper).upstream)
+	}
+
+	want := []*url.URL{up1, up2, up1, up2}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("round robin order mismatch at %d: got %v want %v", i, seen[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package sponge
+
+import (
+	"context"
+	"encoding"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+/*
+RedisCache is a Cache backed by Redis, useful for sharing a warm cache across
+multiple SpongeHandler processes. Cached SpongeProxyResult values must also
+implement encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+*/
+type RedisCache struct {
+	// Client is the Redis connection used for all cache operations.
+	Client *redis.Client
+
+	// New returns a fresh, empty SpongeProxyResult (typically a pointer) for
+	// Get to unmarshal a stored entry into.
+	New func() SpongeProxyResult
+
+	// Prefix is prepended to every cache key, useful for sharing a Redis
+	// instance between multiple SpongeHandlers.
+	Prefix string
+}
+
+func (c *RedisCache) Get(key string) (SpongeProxyResult, bool) {
+	data, err := c.Client.Get(context.Background(), c.Prefix+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	value := c.New()
+	unmarshaler, ok := value.(encoding.BinaryUnmarshaler)
+	if !ok {
+		log.Printf("sponge: RedisCache.New() returned %T, which does not implement encoding.BinaryUnmarshaler; every Get will miss", value)
+		return nil, false
+	}
+
+	if err := unmarshaler.UnmarshalBinary(data); err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+func (c *RedisCache) Set(key string, val SpongeProxyResult, ttl time.Duration) {
+	marshaler, ok := val.(encoding.BinaryMarshaler)
+	if !ok {
+		log.Printf("sponge: %T does not implement encoding.BinaryMarshaler; RedisCache.Set is a no-op for it", val)
+		return
+	}
+
+	data, err := marshaler.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	c.Client.Set(context.Background(), c.Prefix+key, data, ttl)
+}
+
+func (c *RedisCache) Delete(key string) {
+	c.Client.Del(context.Background(), c.Prefix+key)
+}
+
+func (c *RedisCache) Purge() {
+	ctx := context.Background()
+	iter := c.Client.Scan(ctx, 0, c.Prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		c.Client.Del(ctx, iter.Val())
+	}
+}
+
+var _ Cache = (*RedisCache)(nil)
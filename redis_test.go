@@ -0,0 +1,24 @@
+package sponge
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type nonMarshalableResult struct{}
+
+func (nonMarshalableResult) WriteToHTTP(w http.ResponseWriter) error { return nil }
+
+func (nonMarshalableResult) Equal(other SpongeProxyResult) bool {
+	_, ok := other.(nonMarshalableResult)
+	return ok
+}
+
+// Set must no-op (not panic, not reach Redis) when val doesn't implement
+// encoding.BinaryMarshaler.
+func TestRedisCacheSetNoOpsForNonMarshalableResult(t *testing.T) {
+	c := &RedisCache{Client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})}
+	c.Set("k", nonMarshalableResult{}, 0)
+}
@@ -23,6 +23,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 /*
@@ -70,10 +72,29 @@ type SpongeHandler struct {
 	// How frequently to check the cache.
 	CacheRunExpiration time.Duration
 
-	cache       map[string]SpongeProxyResult // The actual cache
-	cacheExpire map[string]time.Time         // expire management
-	mutex       sync.Mutex
-	serveMutex  sync.Mutex
+	// Metrics, if set, records cache hit/miss and backend request counters.
+	// Leave nil to skip metrics entirely.
+	Metrics *Metrics
+
+	// Tracer, if set, wraps each backend poll in a span carrying the cache
+	// key and outcome. Leave nil to skip tracing entirely.
+	Tracer trace.Tracer
+
+	cache Cache
+
+	inflight   map[string]*inflightCall
+	inflightMu sync.Mutex
+}
+
+/*
+inflightCall tracks a single in-progress checkTick for a cache key, so that
+concurrent requests for the same key coalesce onto one backend poll instead of
+each triggering their own.
+*/
+type inflightCall struct {
+	result SpongeProxyResult
+	err    error
+	done   chan struct{}
 }
 
 /*
@@ -123,40 +144,30 @@ type tickChan struct {
 }
 
 /*
-Initialize a SpongeHandler. If the argument is nil, it will create the map it
-needs for the cache. Otherwise, you can pass another cache in (for it to share,
-or to restore a cache) and it will be used.
+Initialize a SpongeHandler. If the argument is nil, it will create a default
+in-memory LRUCache. Otherwise, you can pass another Cache in (for it to share,
+to restore state, or to back it with something like Redis) and it will be
+used.
 */
-func (sh *SpongeHandler) Init(cache map[string]SpongeProxyResult) {
+func (sh *SpongeHandler) Init(cache Cache) {
 	if cache == nil {
-		sh.cache = make(map[string]SpongeProxyResult)
+		sh.cache = NewLRUCache(DefaultMaxEntries)
 	} else {
 		sh.cache = cache
 	}
 
-	sh.cacheExpire = make(map[string]time.Time)
+	sh.inflight = make(map[string]*inflightCall)
 
 	go sh.doCacheExpiry()
 }
 
 /*
-Run the cache expriation -- runs as a goroutine, similar to a Monitor.
+doCacheExpiry historically swept expired entries out of the cache on a timer.
+Cache implementations now own their own expiration (LRUCache checks
+expiresAt on Get; RedisCache relies on Redis' own TTL), so this is a no-op
+kept for callers that still expect Init to start a background goroutine.
 */
 func (sh *SpongeHandler) doCacheExpiry() {
-	expiration_time := sh.CacheExtraExpiration + (time.Duration(sh.TickCount * int64(sh.TickTime)))
-
-	for {
-		sh.mutex.Lock()
-		for key, value := range sh.cacheExpire {
-			if time.Now().Add(-expiration_time).After(value.Add(sh.CacheExtraExpiration)) {
-				delete(sh.cacheExpire, key)
-				delete(sh.cache, key)
-			}
-		}
-		sh.mutex.Unlock()
-
-		time.Sleep(sh.CacheRunExpiration)
-	}
 }
 
 /*
@@ -165,29 +176,40 @@ ticks has exhausted.
 */
 func (sh *SpongeHandler) checkTick(key string, request *http.Request) (SpongeProxyResult, error) {
 
-	sp := make(chan tickChan)
+	sp := make(chan tickChan, 1)
+	var once sync.Once
+	send := func(result SpongeProxyResult, err error) {
+		once.Do(func() {
+			sp <- tickChan{result, err}
+		})
+	}
 
 	go func() {
-		for i := int64(0); i < sh.TickCount; i++ {
-			result, err := sh.Proxy.MakeBackendRequest(request)
+		var last tickChan
 
-			if val, ok := sh.GetCache(key); !ok || !val.Equal(result) {
+		for i := int64(0); i < sh.TickCount; i++ {
+			result, changed, err := sh.pollBackend(key, request)
+			last = tickChan{result, err}
 
+			if changed {
 				if err != nil {
 					log.Println("error:", err)
+					time.Sleep(sh.TickTime)
 					continue
 				}
 
 				sh.SetCache(request, result)
 			}
 
-			if sp != nil {
-				sp <- tickChan{result, err}
-				sp = nil
-			}
-
+			send(result, err)
 			time.Sleep(sh.TickTime)
 		}
+
+		// If every tick errored, the loop above never sent: signal the last
+		// observed outcome anyway, so a permanently-failing backend returns
+		// its error to the caller instead of leaving checkTick -- and every
+		// singleflight caller waiting behind it -- blocked forever.
+		send(last.result, last.err)
 	}()
 
 	res := <-sp
@@ -195,35 +217,128 @@ func (sh *SpongeHandler) checkTick(key string, request *http.Request) (SpongePro
 	return res.result, res.err
 }
 
+/*
+pollBackend makes one backend request for key, recording its outcome to
+Metrics and, when Tracer is set, wrapping the call in a span carrying the
+cache key and outcome.
+*/
+func (sh *SpongeHandler) pollBackend(key string, request *http.Request) (SpongeProxyResult, bool, error) {
+	start := time.Now()
+	_, hadPrev := sh.GetCache(key)
+
+	request, span := startBackendSpan(sh.Tracer, request, key)
+	result, changed, err := sh.fetchBackendResult(key, request)
+
+	outcome := backendOutcome(hadPrev, changed, err)
+	span.end(outcome, err)
+
+	sh.Metrics.recordBackendRequest(outcome, time.Since(start))
+	if outcome == "state_change" {
+		sh.Metrics.recordStateChange()
+	}
+
+	return result, changed, err
+}
+
+/*
+fetchBackendResult makes one backend request for key, preferring a
+conditional GET via SpongeConditionalProxy when Proxy implements it and a
+cached entry exists to validate against. changed mirrors the Equal()
+comparison checkTick has always made for plain backends, but for a
+conditional proxy it comes straight from the backend (e.g. a 304 response)
+instead of a full Equal call.
+*/
+func (sh *SpongeHandler) fetchBackendResult(key string, request *http.Request) (result SpongeProxyResult, changed bool, err error) {
+	if condProxy, ok := sh.Proxy.(SpongeConditionalProxy); ok {
+		if prev, ok := sh.GetCache(key); ok {
+			request = request.Clone(request.Context())
+			setConditionalHeaders(request, prev)
+
+			result, changed, err = condProxy.MakeConditionalBackendRequest(request, prev)
+			if !changed {
+				result = prev
+			}
+
+			return result, changed, err
+		}
+	}
+
+	result, err = sh.Proxy.MakeBackendRequest(request)
+
+	prev, ok := sh.GetCache(key)
+	changed = !ok || !prev.Equal(result)
+
+	return result, changed, err
+}
+
+/*
+singleflight coalesces concurrent checkTick calls for the same cache key into
+one backend poll. Callers for other keys are unaffected -- only requests
+racing on the same key share a result.
+*/
+func (sh *SpongeHandler) singleflight(key string, request *http.Request) (SpongeProxyResult, error) {
+	sh.inflightMu.Lock()
+	if call, ok := sh.inflight[key]; ok {
+		sh.inflightMu.Unlock()
+		<-call.done
+		return call.result, call.err
+	}
+
+	call := &inflightCall{done: make(chan struct{})}
+	sh.inflight[key] = call
+	sh.inflightMu.Unlock()
+
+	call.result, call.err = sh.checkTick(key, request)
+
+	sh.inflightMu.Lock()
+	delete(sh.inflight, key)
+	sh.inflightMu.Unlock()
+
+	close(call.done)
+
+	return call.result, call.err
+}
+
 func (sh *SpongeHandler) GetCache(key string) (SpongeProxyResult, bool) {
-	sh.mutex.Lock()
-	defer sh.mutex.Unlock()
-	value, ok := sh.cache[key]
-	return value, ok
+	return sh.cache.Get(key)
 }
 
 /*
 Function to update the cache and expiration at the same time.
 */
 func (sh *SpongeHandler) SetCache(request *http.Request, value SpongeProxyResult) {
-	sh.mutex.Lock()
-	defer sh.mutex.Unlock()
 	key := sh.Proxy.MakeCacheKey(request)
-	sh.cache[key] = value
-	sh.cacheExpire[key] = time.Now()
+	expiration := sh.CacheExtraExpiration + (time.Duration(sh.TickCount * int64(sh.TickTime)))
+
+	if expirer, ok := sh.Proxy.(SpongeExpirer); ok {
+		expiration = expirer.Expiration(request, value)
+	}
+
+	sh.cache.Set(key, value, expiration)
 }
 
 /*
 http.Server handler -- actually responds to the request.
 */
 func (sh *SpongeHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	sh.serveMutex.Lock()
-	defer sh.serveMutex.Unlock()
 	key := sh.Proxy.MakeCacheKey(request)
 
 	result, ok := sh.GetCache(key)
 	if !ok {
-		result, _ = sh.checkTick(key, request)
+		sh.Metrics.recordCacheMiss()
+
+		var err error
+		result, err = sh.singleflight(key, request)
+		if err != nil {
+			sh.Proxy.HandleError(err, writer)
+			return
+		}
+	} else {
+		sh.Metrics.recordCacheHit()
+	}
+
+	if sizer, ok := sh.cache.(CacheSizer); ok {
+		sh.Metrics.setCacheEntries(int64(sizer.Len()))
 	}
 
 	result.WriteToHTTP(writer)
@@ -0,0 +1,136 @@
+package sponge
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+countingProxy is a minimal SpongeProxy that counts MakeBackendRequest calls
+and can be made to always error, for exercising singleflight coalescing and
+checkTick's failure handling.
+*/
+type countingProxy struct {
+	mu         sync.Mutex
+	calls      int
+	delay      time.Duration
+	err        error
+	handledErr error
+}
+
+func (p *countingProxy) MakeCacheKey(request *http.Request) string {
+	return "k"
+}
+
+func (p *countingProxy) MakeBackendRequest(request *http.Request) (SpongeProxyResult, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+
+	if p.delay > 0 {
+		time.Sleep(p.delay)
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return countingResult{val: 1}, nil
+}
+
+func (p *countingProxy) HandleError(err error, writer http.ResponseWriter) {
+	p.mu.Lock()
+	p.handledErr = err
+	p.mu.Unlock()
+
+	writer.WriteHeader(http.StatusBadGateway)
+}
+
+func (p *countingProxy) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+type countingResult struct {
+	val int
+}
+
+func (r countingResult) WriteToHTTP(w http.ResponseWriter) error { return nil }
+
+func (r countingResult) Equal(other SpongeProxyResult) bool {
+	o, ok := other.(countingResult)
+	return ok && o.val == r.val
+}
+
+// Concurrent cache misses for the same key must coalesce onto a single
+// backend poll instead of each caller triggering its own.
+func TestSingleflightCoalescesConcurrentMisses(t *testing.T) {
+	proxy := &countingProxy{delay: 20 * time.Millisecond}
+	sh := &SpongeHandler{TickTime: time.Millisecond, TickCount: 1, Proxy: proxy}
+	sh.Init(nil)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			sh.singleflight("k", httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	wg.Wait()
+
+	if got := proxy.callCount(); got != 1 {
+		t.Fatalf("expected 1 backend call for %d concurrent callers, got %d", callers, got)
+	}
+}
+
+// A backend that errors on every tick must still cause checkTick to return,
+// not hang forever -- which would otherwise wedge every singleflight caller
+// waiting behind it.
+func TestCheckTickAlwaysErroringBackendDoesNotHang(t *testing.T) {
+	wantErr := errors.New("backend down")
+	proxy := &countingProxy{err: wantErr}
+	sh := &SpongeHandler{TickTime: time.Millisecond, TickCount: 3, Proxy: proxy}
+	sh.Init(nil)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sh.checkTick("k", httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("expected %v, got %v", wantErr, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("checkTick hung on a permanently failing backend")
+	}
+}
+
+// ServeHTTP must route a singleflight/checkTick error to Proxy.HandleError
+// instead of discarding it and writing a nil result to the client.
+func TestServeHTTPRoutesBackendErrorToHandleError(t *testing.T) {
+	wantErr := errors.New("backend down")
+	proxy := &countingProxy{err: wantErr}
+	sh := &SpongeHandler{TickTime: time.Millisecond, TickCount: 1, Proxy: proxy}
+	sh.Init(nil)
+
+	w := httptest.NewRecorder()
+	sh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !errors.Is(proxy.handledErr, wantErr) {
+		t.Fatalf("expected HandleError to be called with %v, got %v", wantErr, proxy.handledErr)
+	}
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected HandleError's status code %d to reach the client, got %d", http.StatusBadGateway, w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package sponge
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+backendSpan is a nil-safe wrapper around the span covering one backend poll.
+With a nil Tracer, startBackendSpan returns a backendSpan whose methods are no-ops, so
+callers don't need to branch on whether tracing is enabled.
+*/
+type backendSpan struct {
+	span trace.Span
+}
+
+// startBackendSpan begins a span named "sponge.pollBackend" for key, rooted
+// in request's context, when tracer is non-nil. It returns the (possibly
+// unmodified) request carrying the span's context, and the span wrapper to
+// finish the call with.
+func startBackendSpan(tracer trace.Tracer, request *http.Request, key string) (*http.Request, backendSpan) {
+	if tracer == nil {
+		return request, backendSpan{}
+	}
+
+	ctx, span := tracer.Start(request.Context(), "sponge.pollBackend")
+	span.SetAttributes(attribute.String("sponge.cache_key", key))
+
+	return request.WithContext(ctx), backendSpan{span: span}
+}
+
+// end records outcome and err, if any, then closes the span.
+func (s backendSpan) end(outcome string, err error) {
+	if s.span == nil {
+		return
+	}
+
+	s.span.SetAttributes(attribute.String("sponge.outcome", outcome))
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
+
+// backendOutcome classifies a poll result for metrics labels and span
+// attributes: "error" on failure, "miss" when there was no prior cached
+// value for the key, "state_change" when the backend's result differed from
+// what was cached, otherwise "hit".
+func backendOutcome(hadPrev, changed bool, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case !hadPrev:
+		return "miss"
+	case changed:
+		return "state_change"
+	default:
+		return "hit"
+	}
+}